@@ -52,6 +52,9 @@ import (
 )
 
 // Read reads to string s from connection c only once or until timeout (if set for c)
+//
+// Deprecated: a single fixed-size read truncates anything longer than 1024
+// bytes. Use NewSession or Parser instead.
 func Read(c net.Conn) (s string, err error) {
 	b := make([]byte, 1024)
 	var n int
@@ -65,6 +68,13 @@ func Read(c net.Conn) (s string, err error) {
 }
 
 // ReadLines collects input into string s from connection c until meets empty line or timeout occurred (if it set for c)
+//
+// Deprecated: use NewSession or Parser.ReadEnv instead. ReadLines reads
+// directly off c rather than through a buffered reader, so it can be mixed
+// with other unbuffered calls like Read on the same connection without
+// losing bytes read ahead of the blank line - but, like Read, a single
+// header line longer than one 1024-byte chunk is still handled only
+// because it accumulates across reads, not because it's parsed robustly.
 func ReadLines(c net.Conn) (s string, err error) {
 	b := make([]byte, 1024)
 	var n int
@@ -86,6 +96,8 @@ func ReadLines(c net.Conn) (s string, err error) {
 }
 
 // Parse parses text (AGI vars) into map m and return it
+//
+// Deprecated: use NewSession or Parser.ReadEnv.
 func Parse(s string) (m map[string]string) {
 	m = make(map[string]string)
 	for _, val := range strings.Split(s, "\n") {
@@ -99,6 +111,9 @@ func Parse(s string) (m map[string]string) {
 }
 
 // ReadMap read agi input into map m from connection c and return it
+//
+// Deprecated: use NewSession, which wraps this same env block in a Session
+// ready to issue typed commands on.
 func ReadMap(c net.Conn) (m map[string]string, err error) {
 	var s string
 	s, err = ReadLines(c)
@@ -0,0 +1,86 @@
+package ami
+
+import "strconv"
+
+// OriginateRequest describes an outbound call for Originate.
+type OriginateRequest struct {
+	Channel   string
+	Context   string
+	Exten     string
+	Priority  int
+	Timeout   int // milliseconds
+	CallerID  string
+	Variables map[string]string
+}
+
+// Originate places an outbound call on Channel into Context/Exten/Priority.
+func (c *Client) Originate(req OriginateRequest) error {
+	fields := map[string]string{
+		"Channel":  req.Channel,
+		"Context":  req.Context,
+		"Exten":    req.Exten,
+		"Priority": strconv.Itoa(req.Priority),
+	}
+	if req.Timeout > 0 {
+		fields["Timeout"] = strconv.Itoa(req.Timeout)
+	}
+	if req.CallerID != "" {
+		fields["CallerID"] = req.CallerID
+	}
+	var vars [][2]string
+	for k, v := range req.Variables {
+		vars = append(vars, [2]string{"Variable", k + "=" + v})
+	}
+
+	_, err := c.Action("Originate", fields, vars...)
+	return err
+}
+
+// Redirect moves channel into context/exten/priority mid-call.
+func (c *Client) Redirect(channel, context, exten string, priority int) error {
+	_, err := c.Action("Redirect", map[string]string{
+		"Channel":  channel,
+		"Context":  context,
+		"Exten":    exten,
+		"Priority": strconv.Itoa(priority),
+	})
+	return err
+}
+
+// Bridge joins channel1 and channel2 into the same bridge.
+func (c *Client) Bridge(channel1, channel2 string) error {
+	_, err := c.Action("Bridge", map[string]string{
+		"Channel1": channel1,
+		"Channel2": channel2,
+	})
+	return err
+}
+
+// Hangup terminates channel.
+func (c *Client) Hangup(channel string) error {
+	_, err := c.Action("Hangup", map[string]string{"Channel": channel})
+	return err
+}
+
+// DBGet reads key from family in the Asterisk database. Asterisk acks the
+// action immediately and delivers the value in a follow-up DBGetResponse
+// event sharing the action's ActionID, so this waits for that event rather
+// than reading the inline response.
+func (c *Client) DBGet(family, key string) (string, error) {
+	resp, err := c.actionEvent("DBGet", map[string]string{"Family": family, "Key": key}, "DBGetResponse")
+	if err != nil {
+		return "", err
+	}
+	return resp["Val"], nil
+}
+
+// DBPut stores value under key in family in the Asterisk database.
+func (c *Client) DBPut(family, key, value string) error {
+	_, err := c.Action("DBPut", map[string]string{"Family": family, "Key": key, "Val": value})
+	return err
+}
+
+// PJSIPShowEndpoints lists configured PJSIP endpoints and their state.
+func (c *Client) PJSIPShowEndpoints() ([]map[string]string, error) {
+	return c.actionList("PJSIPShowEndpoints", nil, "EndpointListComplete")
+}
@@ -0,0 +1,349 @@
+// Package ami implements a minimal Asterisk Manager Interface client, so an
+// application using agi for inbound calls can also issue outbound control
+// actions against the same Asterisk instance.
+package ami
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gbazil/agi/internal/proto"
+)
+
+// ErrClosed is returned by Action and Events once the client has been closed.
+var ErrClosed = errors.New("ami: client closed")
+
+// Event is a single "Event: ..." message pushed by Asterisk outside of an
+// action response.
+type Event struct {
+	Fields map[string]string
+}
+
+// Name returns the Event field identifying the event, e.g. "FullyBooted".
+func (e Event) Name() string {
+	return e.Fields["Event"]
+}
+
+// Client is a connection to the Asterisk Manager Interface.
+type Client struct {
+	addr, user, secret string
+
+	mu      sync.Mutex
+	conn    net.Conn
+	done    chan struct{} // closed by readLoop when the current conn dies
+	nextID  uint64
+	pending map[string]chan map[string]string
+	events  chan Event
+	closing chan struct{}
+	closed  bool
+
+	writeMu sync.Mutex // serializes writes to conn, independent of mu
+}
+
+// Dial connects to the AMI listener at addr and logs in as user/secret.
+func Dial(addr, user, secret string) (*Client, error) {
+	c := &Client{
+		addr:    addr,
+		user:    user,
+		secret:  secret,
+		pending: make(map[string]chan map[string]string),
+		events:  make(chan Event, 64),
+		closing: make(chan struct{}),
+	}
+
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+
+	go c.reconnectLoop()
+
+	return c, nil
+}
+
+func (c *Client) connect() error {
+	conn, err := net.Dial("tcp", c.addr)
+	if err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(conn)
+	if _, err := r.ReadString('\n'); err != nil { // banner: "Asterisk Call Manager/x.y.z"
+		conn.Close()
+		return err
+	}
+
+	done := make(chan struct{})
+
+	c.mu.Lock()
+	c.conn = conn
+	c.done = done
+	c.mu.Unlock()
+
+	go c.readLoop(conn, r, done)
+
+	if _, err := c.Action("Login", map[string]string{
+		"Username": c.user,
+		"Secret":   c.secret,
+	}); err != nil {
+		conn.Close()
+		return err
+	}
+
+	return nil
+}
+
+// reconnectLoop redials with exponential backoff whenever the connection is
+// lost, until Close is called.
+func (c *Client) reconnectLoop() {
+	backoff := time.Second
+
+	for {
+		c.mu.Lock()
+		done := c.done
+		c.mu.Unlock()
+		if done == nil {
+			return
+		}
+		<-done
+
+		select {
+		case <-c.closing:
+			return
+		default:
+		}
+
+		for {
+			if err := c.connect(); err == nil {
+				backoff = time.Second
+				break
+			}
+
+			select {
+			case <-c.closing:
+				return
+			case <-time.After(backoff):
+			}
+
+			if backoff < 30*time.Second {
+				backoff *= 2
+			}
+		}
+	}
+}
+
+func (c *Client) readLoop(conn net.Conn, r *bufio.Reader, done chan struct{}) {
+	defer close(done)
+
+	for {
+		fields, err := proto.ReadHeaders(r)
+		if err != nil {
+			return
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		if id, ok := fields["ActionID"]; ok {
+			c.mu.Lock()
+			ch, ok := c.pending[id]
+			c.mu.Unlock()
+
+			if ok {
+				// The pending entry is removed by whoever registered it
+				// (Action/actionList), not here: a list action keeps its
+				// entry alive across several messages sharing one ActionID.
+				select {
+				case ch <- fields:
+				case <-done:
+				}
+				continue
+			}
+		}
+
+		select {
+		case c.events <- Event{Fields: fields}:
+		default:
+		}
+	}
+}
+
+// Events returns the channel of unsolicited events pushed by Asterisk.
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// newPending allocates an ActionID and registers a channel of the given
+// buffer size to receive every message tagged with it, along with the done
+// channel of the connection the action will be sent on.
+func (c *Client) newPending(buf int) (id string, ch chan map[string]string, done chan struct{}, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return "", nil, nil, ErrClosed
+	}
+
+	c.nextID++
+	id = strconv.FormatUint(c.nextID, 10)
+	ch = make(chan map[string]string, buf)
+	c.pending[id] = ch
+	done = c.done
+
+	return id, ch, done, nil
+}
+
+func (c *Client) deletePending(id string) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// writeAction writes one AMI action frame. It holds writeMu only for the
+// duration of the write, so concurrent Action calls never interleave bytes
+// on the wire.
+func (c *Client) writeAction(action, id string, fields map[string]string, extra [][2]string) error {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return errors.New("ami: not connected")
+	}
+
+	var buf []byte
+	buf = append(buf, fmt.Sprintf("Action: %s\r\n", action)...)
+	buf = append(buf, fmt.Sprintf("ActionID: %s\r\n", id)...)
+	for k, v := range fields {
+		buf = append(buf, fmt.Sprintf("%s: %s\r\n", k, v)...)
+	}
+	for _, kv := range extra {
+		buf = append(buf, fmt.Sprintf("%s: %s\r\n", kv[0], kv[1])...)
+	}
+	buf = append(buf, "\r\n"...)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := conn.Write(buf)
+	return err
+}
+
+// Action sends action with the given fields and waits for the correlated
+// response. extra carries additional header lines that must repeat a key
+// (e.g. "Variable"), which a map can't represent.
+//
+// The wait unblocks with an error if the underlying connection dies or the
+// client is closed before a response arrives, rather than hanging forever.
+func (c *Client) Action(action string, fields map[string]string, extra ...[2]string) (map[string]string, error) {
+	id, ch, done, err := c.newPending(1)
+	if err != nil {
+		return nil, err
+	}
+	defer c.deletePending(id)
+
+	if err := c.writeAction(action, id, fields, extra); err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp["Response"] == "Error" {
+			return resp, fmt.Errorf("ami: %s failed: %s", action, resp["Message"])
+		}
+		return resp, nil
+	case <-done:
+		return nil, errors.New("ami: connection lost while waiting for response")
+	case <-c.closing:
+		return nil, ErrClosed
+	}
+}
+
+// actionList sends action and collects every message tagged with its
+// ActionID until one whose Event field equals completeEvent arrives, which
+// is how Asterisk terminates "show list" style responses (e.g.
+// PJSIPShowEndpoints/EndpointListComplete). Like Action, it unblocks with an
+// error if the connection dies or the client is closed mid-wait, instead of
+// racing general Events() consumers for the same messages.
+func (c *Client) actionList(action string, fields map[string]string, completeEvent string) ([]map[string]string, error) {
+	id, ch, done, err := c.newPending(64)
+	if err != nil {
+		return nil, err
+	}
+	defer c.deletePending(id)
+
+	if err := c.writeAction(action, id, fields, nil); err != nil {
+		return nil, err
+	}
+
+	var list []map[string]string
+	for {
+		select {
+		case fields := <-ch:
+			if fields["Response"] == "Error" {
+				return nil, fmt.Errorf("ami: %s failed: %s", action, fields["Message"])
+			}
+			if fields["Event"] == completeEvent {
+				return list, nil
+			}
+			if fields["Event"] != "" {
+				list = append(list, fields)
+			}
+		case <-done:
+			return nil, errors.New("ami: connection lost while waiting for response")
+		case <-c.closing:
+			return nil, ErrClosed
+		}
+	}
+}
+
+// actionEvent sends action and returns the first event named event sharing
+// its ActionID, skipping the inline "Response: Success ... Result will
+// follow" ack that actions like DBGet send ahead of their real answer.
+// Like Action, it unblocks with an error if the connection dies or the
+// client is closed mid-wait.
+func (c *Client) actionEvent(action string, fields map[string]string, event string) (map[string]string, error) {
+	id, ch, done, err := c.newPending(4)
+	if err != nil {
+		return nil, err
+	}
+	defer c.deletePending(id)
+
+	if err := c.writeAction(action, id, fields, nil); err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case msg := <-ch:
+			if msg["Response"] == "Error" {
+				return nil, fmt.Errorf("ami: %s failed: %s", action, msg["Message"])
+			}
+			if msg["Event"] == event {
+				return msg, nil
+			}
+		case <-done:
+			return nil, errors.New("ami: connection lost while waiting for response")
+		case <-c.closing:
+			return nil, ErrClosed
+		}
+	}
+}
+
+// Close stops the client and closes the underlying connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	conn := c.conn
+	c.mu.Unlock()
+
+	close(c.closing)
+
+	if conn != nil {
+		return conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package agi
+
+// Event is an asynchronous notification Asterisk can push to a Session
+// outside of a command reply, e.g. a HANGUP while a command is blocking.
+type Event struct {
+	Name string
+	Data map[string]string
+}
+
+func (s *Session) dispatch(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.Name == "HANGUP" {
+		s.hungUp = true
+	}
+
+	for _, ch := range s.subs[e.Name] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	for _, ch := range s.subs["*"] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (s *Session) isHungUp() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hungUp
+}
+
+// Subscribe returns a channel of events matching one of names. With no
+// names given, every event is delivered. The channel is buffered; slow
+// consumers drop events rather than block the read loop.
+func (s *Session) Subscribe(names ...string) <-chan Event {
+	ch := make(chan Event, 8)
+
+	if len(names) == 0 {
+		names = []string{"*"}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range names {
+		s.subs[n] = append(s.subs[n], ch)
+	}
+
+	return ch
+}
+
+// Events returns a channel delivering every asynchronous event for the
+// session, equivalent to Subscribe() with no filter.
+func (s *Session) Events() <-chan Event {
+	return s.Subscribe()
+}
@@ -0,0 +1,31 @@
+// Package proto holds the line-oriented "Key: Value\r\n" header parsing
+// shared by the AGI env block and the AMI wire format, so the two packages
+// don't duplicate it.
+package proto
+
+import (
+	"bufio"
+	"strings"
+)
+
+// ReadHeaders reads "Key: Value" lines from r until a blank line and
+// returns them as a map. The blank line is consumed.
+func ReadHeaders(r *bufio.Reader) (map[string]string, error) {
+	m := make(map[string]string)
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return m, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			return m, nil
+		}
+
+		if k, v, ok := strings.Cut(line, ": "); ok {
+			m[k] = v
+		}
+	}
+}
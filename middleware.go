@@ -0,0 +1,67 @@
+package agi
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// LoggingMiddleware logs the script, duration and error (if any) of every
+// call using logger.
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(s *Session) error {
+			start := time.Now()
+			err := next(s)
+			logger.Printf("agi: %s completed in %s: %v", s.Env()["agi_network_script"], time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// RecoverMiddleware turns a panic in the wrapped handler into an error so
+// one bad call can't take down the server.
+func RecoverMiddleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(s *Session) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("agi: handler panic: %v", r)
+				}
+			}()
+			return next(s)
+		}
+	}
+}
+
+// TimeoutMiddleware fails the call with an error if it runs longer than d.
+// The underlying connection is not closed; pair this with Server.CallTimeout
+// or your own deadline to actually abort in-flight I/O.
+func TimeoutMiddleware(d time.Duration) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(s *Session) error {
+			done := make(chan error, 1)
+			go func() { done <- next(s) }()
+
+			select {
+			case err := <-done:
+				return err
+			case <-time.After(d):
+				return fmt.Errorf("agi: handler timed out after %s", d)
+			}
+		}
+	}
+}
+
+// MetricsMiddleware reports call counts and durations for the script being
+// handled via observe.
+func MetricsMiddleware(observe func(script string, dur time.Duration, err error)) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(s *Session) error {
+			start := time.Now()
+			err := next(s)
+			observe(s.Env()["agi_network_script"], time.Since(start), err)
+			return err
+		}
+	}
+}
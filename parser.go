@@ -0,0 +1,125 @@
+package agi
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gbazil/agi/internal/proto"
+)
+
+var resultPattern = regexp.MustCompile(`result=(-?\d+)(?:\s+\(([^)]*)\))?(?:\s+endpos=(\d+))?`)
+
+// Reply is one parsed AGI command reply, covering both the single-line
+// "200 result=..." form and the multi-line "5xx ... 5xx End of proper
+// usage." form.
+type Reply struct {
+	Code   int
+	Result int
+	Data   string
+	Endpos int
+	Raw    string
+}
+
+// ProtocolError reports a malformed AGI frame, identifying the offending line.
+type ProtocolError struct {
+	Line string
+	Msg  string
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("agi: %s: %q", e.Msg, e.Line)
+}
+
+// Parser reads AGI env blocks and command replies off a buffered reader,
+// correctly handling arbitrary-length headers and multi-line replies
+// regardless of how the underlying TCP reads are chunked - unlike Read and
+// ReadLines, which operate on a single fixed-size read.
+type Parser struct {
+	r *bufio.Reader
+}
+
+// NewParser returns a Parser reading from r.
+func NewParser(r io.Reader) *Parser {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Parser{r: br}
+}
+
+// ReadEnv reads the agi_* header block Asterisk sends when a call connects,
+// returning a ProtocolError if agi_network is missing.
+func (p *Parser) ReadEnv() (map[string]string, error) {
+	m, err := proto.ReadHeaders(p.r)
+	if err != nil {
+		return nil, err
+	}
+
+	if m["agi_network"] != "yes" {
+		return nil, &ProtocolError{Line: "agi_network", Msg: "missing or invalid agi_network header"}
+	}
+
+	return m, nil
+}
+
+func (p *Parser) readLine() (string, error) {
+	line, err := p.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// ReadReply reads exactly one AGI reply frame.
+func (p *Parser) ReadReply() (Reply, error) {
+	return readReply(p.readLine)
+}
+
+// readReply assembles a Reply from successive lines produced by next, so
+// the same framing logic can run directly off a bufio.Reader (Parser) or
+// off a channel fed by a background read loop (Session).
+func readReply(next func() (string, error)) (Reply, error) {
+	line, err := next()
+	if err != nil {
+		return Reply{}, err
+	}
+
+	if len(line) < 3 {
+		return Reply{}, &ProtocolError{Line: line, Msg: "reply too short to contain a status code"}
+	}
+
+	code, err := strconv.Atoi(line[:3])
+	if err != nil {
+		return Reply{}, &ProtocolError{Line: line, Msg: "reply does not start with a status code"}
+	}
+
+	raw := line
+	if code != 200 {
+		for strings.HasPrefix(line, strconv.Itoa(code)+"-") {
+			line, err = next()
+			if err != nil {
+				return Reply{}, err
+			}
+			raw += "\n" + line
+			if strings.HasPrefix(line, strconv.Itoa(code)+" ") {
+				break
+			}
+		}
+		return Reply{Code: code, Raw: raw}, nil
+	}
+
+	reply := Reply{Code: code, Raw: raw}
+	if m := resultPattern.FindStringSubmatch(line); m != nil {
+		reply.Result, _ = strconv.Atoi(m[1])
+		reply.Data = m[2]
+		if m[3] != "" {
+			reply.Endpos, _ = strconv.Atoi(m[3])
+		}
+	}
+
+	return reply, nil
+}
@@ -0,0 +1,86 @@
+package agi
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParserReadReplySingleLine200(t *testing.T) {
+	p := NewParser(bufio.NewReader(strings.NewReader("200 result=1 endpos=1234\n")))
+
+	reply, err := p.ReadReply()
+	if err != nil {
+		t.Fatalf("ReadReply: %v", err)
+	}
+	if reply.Code != 200 || reply.Result != 1 || reply.Endpos != 1234 {
+		t.Fatalf("got %+v", reply)
+	}
+}
+
+func TestParserReadReplyMultiLine5xx(t *testing.T) {
+	raw := "510-Invalid command syntax.  Proper usage follows:\n" +
+		"510-   RECORD FILE <filename> <format> <escape digits> <timeout>\n" +
+		"510 End of proper usage.\n"
+
+	p := NewParser(bufio.NewReader(strings.NewReader(raw)))
+
+	reply, err := p.ReadReply()
+	if err != nil {
+		t.Fatalf("ReadReply: %v", err)
+	}
+	if reply.Code != 510 {
+		t.Fatalf("Code = %d, want 510", reply.Code)
+	}
+	if !strings.Contains(reply.Raw, "End of proper usage.") {
+		t.Fatalf("Raw = %q, missing terminating line", reply.Raw)
+	}
+	if got := strings.Count(reply.Raw, "\n"); got != 2 {
+		t.Fatalf("Raw has %d newlines, want 2 (all three lines joined)", got)
+	}
+}
+
+func TestParserReadReplyMultiLineAcrossChunkedReads(t *testing.T) {
+	// A chunkReader that only ever hands back a few bytes per Read call
+	// exercises the same split-TCP-read scenario bufio.Reader is meant to
+	// absorb, independent of how the data happens to arrive on the wire.
+	raw := "520-Invalid command syntax.  Proper usage follows:\n" +
+		"520 End of proper usage.\n"
+
+	p := NewParser(bufio.NewReader(&chunkReader{data: raw, n: 3}))
+
+	reply, err := p.ReadReply()
+	if err != nil {
+		t.Fatalf("ReadReply: %v", err)
+	}
+	if reply.Code != 520 {
+		t.Fatalf("Code = %d, want 520", reply.Code)
+	}
+}
+
+// chunkReader returns at most n bytes per Read, to simulate a reply split
+// across multiple TCP reads.
+type chunkReader struct {
+	data string
+	n    int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+
+	n := r.n
+	if n > len(p) {
+		n = len(p)
+	}
+	if n > len(r.data) {
+		n = len(r.data)
+	}
+
+	copy(p, r.data[:n])
+	r.data = r.data[n:]
+
+	return n, nil
+}
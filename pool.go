@@ -0,0 +1,186 @@
+package agi
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrPoolExhausted is returned by Get when the pool has no idle connection,
+// MaxActive has been reached and Wait is false.
+var ErrPoolExhausted = errors.New("agi: connection pool exhausted")
+
+// Pool manages a set of reusable connections, modeled on redigo's redis.Pool,
+// for outbound AGI-style connections (async AGI, proxying to upstream
+// FastAGI targets) and AMI reuse.
+type Pool struct {
+	// Dial creates a new connection when the pool needs one.
+	Dial func() (net.Conn, error)
+
+	// TestOnBorrow, if set, is called on an idle connection before it is
+	// handed out by Get. If it returns an error the connection is closed
+	// and discarded instead.
+	TestOnBorrow func(c net.Conn, idleSince time.Time) error
+
+	// MaxIdle is the maximum number of idle connections kept in the pool.
+	// Zero means no idle connections are retained.
+	MaxIdle int
+
+	// MaxActive is the maximum number of connections allocated by the pool
+	// at a given time, including ones currently in use. Zero means no limit.
+	MaxActive int
+
+	// IdleTimeout closes idle connections older than this when encountered
+	// by Get. Zero means idle connections never expire.
+	IdleTimeout time.Duration
+
+	// Wait, if true, makes Get block until a connection is available
+	// instead of returning ErrPoolExhausted.
+	Wait bool
+
+	mu        sync.Mutex
+	idle      []idleConn
+	active    int
+	waitCount int
+	waitDur   time.Duration
+	cond      *sync.Cond
+	closed    bool
+}
+
+type idleConn struct {
+	c net.Conn
+	t time.Time
+}
+
+// Stats reports the current state of a Pool.
+type Stats struct {
+	ActiveCount  int
+	IdleCount    int
+	WaitCount    int
+	WaitDuration time.Duration
+}
+
+func (p *Pool) condVar() *sync.Cond {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cond == nil {
+		p.cond = sync.NewCond(&p.mu)
+	}
+	return p.cond
+}
+
+// Get returns a connection from the pool, dialing a new one if no idle
+// connection passes TestOnBorrow and MaxActive allows it.
+func (p *Pool) Get() (net.Conn, error) {
+	cond := p.condVar()
+
+	p.mu.Lock()
+	for {
+		if p.closed {
+			p.mu.Unlock()
+			return nil, errors.New("agi: pool closed")
+		}
+
+		for len(p.idle) > 0 {
+			ic := p.idle[len(p.idle)-1]
+			p.idle = p.idle[:len(p.idle)-1]
+
+			if p.IdleTimeout > 0 && time.Since(ic.t) > p.IdleTimeout {
+				ic.c.Close()
+				p.active--
+				continue
+			}
+
+			if p.TestOnBorrow != nil {
+				if err := p.TestOnBorrow(ic.c, ic.t); err != nil {
+					ic.c.Close()
+					p.active--
+					continue
+				}
+			}
+
+			p.mu.Unlock()
+			return ic.c, nil
+		}
+
+		if p.MaxActive == 0 || p.active < p.MaxActive {
+			p.active++
+			p.mu.Unlock()
+
+			c, err := p.Dial()
+			if err != nil {
+				p.mu.Lock()
+				p.active--
+				p.mu.Unlock()
+				cond.Signal()
+				return nil, err
+			}
+			return c, nil
+		}
+
+		if !p.Wait {
+			p.mu.Unlock()
+			return nil, ErrPoolExhausted
+		}
+
+		p.waitCount++
+		start := time.Now()
+		cond.Wait()
+		p.waitDur += time.Since(start)
+	}
+}
+
+// Put returns c to the pool for reuse. If forceClose is true, or the pool
+// already holds MaxIdle idle connections, c is closed instead.
+func (p *Pool) Put(c net.Conn, forceClose bool) error {
+	p.mu.Lock()
+
+	if p.closed || forceClose || len(p.idle) >= p.MaxIdle {
+		p.active--
+		p.mu.Unlock()
+		if p.cond != nil {
+			p.cond.Signal()
+		}
+		return c.Close()
+	}
+
+	p.idle = append(p.idle, idleConn{c: c, t: time.Now()})
+	p.mu.Unlock()
+
+	if p.cond != nil {
+		p.cond.Signal()
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the pool's counters.
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return Stats{
+		ActiveCount:  p.active,
+		IdleCount:    len(p.idle),
+		WaitCount:    p.waitCount,
+		WaitDuration: p.waitDur,
+	}
+}
+
+// Close closes all idle connections and marks the pool closed; connections
+// currently borrowed are closed as they're returned via Put.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	if p.cond != nil {
+		p.cond.Broadcast()
+	}
+
+	for _, ic := range idle {
+		ic.c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package agi
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeConn is a net.Conn stand-in that only tracks whether it's been closed,
+// since Pool only ever dials, closes and hands back connections.
+type fakeConn struct {
+	net.Conn
+	closed int32
+}
+
+func (c *fakeConn) Close() error {
+	atomic.StoreInt32(&c.closed, 1)
+	return nil
+}
+
+func TestPoolGetPutConcurrent(t *testing.T) {
+	var dialed int32
+
+	p := &Pool{
+		Dial: func() (net.Conn, error) {
+			atomic.AddInt32(&dialed, 1)
+			return &fakeConn{}, nil
+		},
+		MaxActive: 4,
+		MaxIdle:   4,
+		Wait:      true,
+	}
+	defer p.Close()
+
+	const workers = 20
+	const iterations = 50
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				c, err := p.Get()
+				if err != nil {
+					t.Errorf("Get: %v", err)
+					return
+				}
+				if err := p.Put(c, false); err != nil {
+					t.Errorf("Put: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := p.Stats()
+	if stats.ActiveCount > 4 {
+		t.Fatalf("ActiveCount = %d, want <= MaxActive (4)", stats.ActiveCount)
+	}
+	if dialed > 4 {
+		t.Fatalf("dialed %d connections, want <= MaxActive (4) since Put returns them for reuse", dialed)
+	}
+}
+
+func TestPoolGetExhaustedWithoutWait(t *testing.T) {
+	p := &Pool{
+		Dial: func() (net.Conn, error) {
+			return &fakeConn{}, nil
+		},
+		MaxActive: 1,
+		Wait:      false,
+	}
+	defer p.Close()
+
+	c, err := p.Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if _, err := p.Get(); err != ErrPoolExhausted {
+		t.Fatalf("Get on exhausted pool = %v, want ErrPoolExhausted", err)
+	}
+
+	if err := p.Put(c, false); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+}
+
+func TestPoolGetWakesWaiterAfterFailedDial(t *testing.T) {
+	proceed := make(chan struct{})
+	dialing := make(chan struct{}, 1) // signals the blocking Dial call has been entered
+	var dialCount int32
+
+	p := &Pool{
+		Dial: func() (net.Conn, error) {
+			if atomic.AddInt32(&dialCount, 1) == 1 {
+				dialing <- struct{}{}
+				<-proceed
+				return nil, errors.New("dial error")
+			}
+			return &fakeConn{}, nil
+		},
+		MaxActive: 1,
+		Wait:      true,
+	}
+	defer p.Close()
+
+	// With MaxActive 1, whichever of these two concurrent Get() calls
+	// grabs the slot first is the only one that ever reaches Dial; the
+	// other necessarily parks in cond.Wait() behind it. Which goroutine
+	// that is isn't deterministic (and doesn't matter) - what matters is
+	// that exactly one blocks in Dial and the other waits for the slot.
+	type result struct {
+		c   net.Conn
+		err error
+	}
+	results := make(chan result, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			c, err := p.Get()
+			results <- result{c, err}
+		}()
+	}
+
+	select {
+	case <-dialing:
+	case <-time.After(time.Second):
+		t.Fatal("neither Get reached Dial")
+	}
+	close(proceed)
+
+	var errCount, okCount int
+	for i := 0; i < 2; i++ {
+		select {
+		case r := <-results:
+			switch {
+			case r.err != nil:
+				errCount++
+			case r.c != nil:
+				okCount++
+				p.Put(r.c, false)
+			}
+		case <-time.After(time.Second):
+			// Without signaling on the failed-dial path, the waiter blocks
+			// forever even though the slot it needed is free again.
+			t.Fatal("a Get never returned; want the waiter to wake after the failed dial")
+		}
+	}
+
+	if errCount != 1 || okCount != 1 {
+		t.Fatalf("got %d failed Get(s) and %d succeeded Get(s), want exactly one of each", errCount, okCount)
+	}
+}
+
+func TestPoolPutClosesBeyondMaxIdle(t *testing.T) {
+	p := &Pool{
+		Dial: func() (net.Conn, error) {
+			return &fakeConn{}, nil
+		},
+		MaxIdle: 1,
+	}
+	defer p.Close()
+
+	c1, _ := p.Get()
+	c2, _ := p.Get()
+
+	if err := p.Put(c1, false); err != nil {
+		t.Fatalf("Put c1: %v", err)
+	}
+	if err := p.Put(c2, false); err != nil {
+		t.Fatalf("Put c2: %v", err)
+	}
+
+	if got := p.Stats().IdleCount; got != 1 {
+		t.Fatalf("IdleCount = %d, want 1 (MaxIdle)", got)
+	}
+	if atomic.LoadInt32(&c1.(*fakeConn).closed) != 0 {
+		t.Fatal("c1 was closed, want it kept idle")
+	}
+	if atomic.LoadInt32(&c2.(*fakeConn).closed) == 0 {
+		t.Fatal("c2 was not closed, want it closed past MaxIdle")
+	}
+}
@@ -0,0 +1,189 @@
+package agi
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+)
+
+// HandlerFunc handles a single FastAGI call.
+type HandlerFunc func(*Session) error
+
+// Middleware wraps a HandlerFunc to add cross-cutting behaviour such as
+// logging, panic recovery or timeouts.
+type Middleware func(HandlerFunc) HandlerFunc
+
+// Server accepts FastAGI connections and dispatches them to handlers
+// registered by agi_network_script, i.e. the path portion of
+// agi://host/<script> as set in the dialplan.
+type Server struct {
+	// ReadTimeout and WriteTimeout bound individual socket reads/writes.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// CallTimeout bounds an entire call, from the env block to the handler
+	// returning. Zero means no deadline beyond Read/WriteTimeout.
+	CallTimeout time.Duration
+
+	// NotFound handles calls whose agi_network_script matches no registered
+	// route. It defaults to hanging up the channel.
+	NotFound HandlerFunc
+
+	mu       sync.Mutex
+	handlers map[string]HandlerFunc
+	mw       []Middleware
+	ln       net.Listener
+	wg       sync.WaitGroup
+	closing  chan struct{}
+	once     sync.Once
+}
+
+// NewServer returns an empty Server ready to have routes registered on it.
+func NewServer() *Server {
+	return &Server{
+		handlers: make(map[string]HandlerFunc),
+		closing:  make(chan struct{}),
+	}
+}
+
+// HandleFunc registers h to handle calls whose agi_network_script is script.
+func (srv *Server) HandleFunc(script string, h HandlerFunc) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.handlers[script] = h
+}
+
+// Use appends mw to the middleware chain applied to every handled call, in
+// the order given.
+func (srv *Server) Use(mw ...Middleware) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.mw = append(srv.mw, mw...)
+}
+
+// ListenAndServe listens for TCP FastAGI connections on addr and serves
+// them until Shutdown is called.
+func (srv *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(ln)
+}
+
+// ListenAndServeTLS listens for AGI-over-TLS connections on addr and serves
+// them until Shutdown is called.
+func (srv *Server) ListenAndServeTLS(addr string, cfg *tls.Config) error {
+	ln, err := tls.Listen("tcp", addr, cfg)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(ln)
+}
+
+// ListenUnix listens for FastAGI connections on the Unix socket path, for
+// use when Asterisk is co-located with the application.
+func (srv *Server) ListenUnix(path string) error {
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(ln)
+}
+
+// Serve accepts connections on ln, handling each in its own goroutine,
+// until ln.Accept fails or Shutdown is called.
+func (srv *Server) Serve(ln net.Listener) error {
+	srv.mu.Lock()
+	srv.ln = ln
+	srv.mu.Unlock()
+
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-srv.closing:
+				return nil
+			default:
+				return err
+			}
+		}
+
+		srv.wg.Add(1)
+		go srv.serveConn(c)
+	}
+}
+
+func (srv *Server) serveConn(c net.Conn) {
+	defer srv.wg.Done()
+	defer c.Close()
+
+	if srv.CallTimeout > 0 {
+		c.SetDeadline(time.Now().Add(srv.CallTimeout))
+	} else if srv.ReadTimeout > 0 || srv.WriteTimeout > 0 {
+		c.SetReadDeadline(time.Now().Add(srv.ReadTimeout))
+		c.SetWriteDeadline(time.Now().Add(srv.WriteTimeout))
+	}
+
+	sess, err := NewSession(c)
+	if err != nil {
+		return
+	}
+
+	h := srv.handlerFor(sess.Env()["agi_network_script"])
+
+	srv.mu.Lock()
+	mw := srv.mw
+	srv.mu.Unlock()
+
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+
+	h(sess)
+}
+
+func (srv *Server) handlerFor(script string) HandlerFunc {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if h, ok := srv.handlers[script]; ok {
+		return h
+	}
+	if srv.NotFound != nil {
+		return srv.NotFound
+	}
+	return func(s *Session) error {
+		_, err := s.Hangup()
+		return err
+	}
+}
+
+// Shutdown stops accepting new connections and waits for in-flight calls to
+// finish, or for ctx to be done.
+func (srv *Server) Shutdown(ctx context.Context) error {
+	srv.once.Do(func() { close(srv.closing) })
+
+	srv.mu.Lock()
+	ln := srv.ln
+	srv.mu.Unlock()
+
+	if ln != nil {
+		ln.Close()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		srv.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,222 @@
+package agi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+// ErrHangup is returned by command helpers when Asterisk reports that the
+// channel hung up while the command was in flight.
+var ErrHangup = errors.New("agi: channel hung up")
+
+// CommandError represents a non-200 AGI reply to a command.
+type CommandError struct {
+	Code    int
+	Message string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("agi: command failed with code %d: %s", e.Code, e.Message)
+}
+
+// Response is a parsed AGI command reply of the form
+// "200 result=<value> [endpos=<n>] [(<data>)]".
+type Response struct {
+	Code   int
+	Result int
+	Data   string
+	Endpos int
+}
+
+// Session is a single FastAGI call: the env Asterisk sends on connect plus
+// the connection used to exchange commands for the rest of the call.
+type Session struct {
+	conn   net.Conn
+	env    map[string]string
+	parser *Parser
+
+	replies chan string
+
+	mu     sync.Mutex
+	subs   map[string][]chan Event
+	hungUp bool
+}
+
+// NewSession reads the AGI env block from c and returns a Session ready to
+// issue commands on. A background goroutine reads the connection for the
+// lifetime of the session, separating synchronous command replies from
+// asynchronous events such as HANGUP; use Events or Subscribe to receive them.
+func NewSession(c net.Conn) (*Session, error) {
+	p := NewParser(c)
+
+	env, err := p.ReadEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Session{
+		conn:    c,
+		env:     env,
+		parser:  p,
+		replies: make(chan string),
+		subs:    make(map[string][]chan Event),
+	}
+
+	go s.readLoop()
+
+	return s, nil
+}
+
+// readLoop demultiplexes the connection: HANGUP notifications are dispatched
+// to event subscribers, everything else is assumed to be (part of) a
+// synchronous command reply and handed to readResponse via s.replies.
+func (s *Session) readLoop() {
+	defer close(s.replies)
+
+	for {
+		line, err := s.parser.readLine()
+		if err != nil {
+			return
+		}
+
+		if strings.EqualFold(line, "HANGUP") {
+			s.dispatch(Event{Name: "HANGUP"})
+			continue
+		}
+
+		s.replies <- line
+	}
+}
+
+// Env returns the agi_* variables Asterisk sent when the call connected.
+func (s *Session) Env() map[string]string {
+	return s.env
+}
+
+// Conn returns the underlying connection, e.g. to adjust deadlines.
+func (s *Session) Conn() net.Conn {
+	return s.conn
+}
+
+func (s *Session) readLine() (string, error) {
+	line, ok := <-s.replies
+	if !ok {
+		if s.isHungUp() {
+			return "", ErrHangup
+		}
+		return "", io.EOF
+	}
+
+	return line, nil
+}
+
+func (s *Session) send(cmd string) (Response, error) {
+	if _, err := WriteLine(s.conn, cmd); err != nil {
+		return Response{}, err
+	}
+
+	return s.readResponse()
+}
+
+func (s *Session) readResponse() (Response, error) {
+	reply, err := readReply(s.readLine)
+	if err != nil {
+		return Response{}, err
+	}
+
+	if reply.Code != 200 {
+		return Response{}, &CommandError{Code: reply.Code, Message: reply.Raw}
+	}
+
+	return Response{Code: reply.Code, Result: reply.Result, Data: reply.Data, Endpos: reply.Endpos}, nil
+}
+
+// Answer answers the channel.
+func (s *Session) Answer() (Response, error) {
+	return s.send("ANSWER")
+}
+
+// Hangup hangs up the current channel, or the named channel if given.
+func (s *Session) Hangup(channel ...string) (Response, error) {
+	if len(channel) > 0 {
+		return s.send(fmt.Sprintf("HANGUP %s", channel[0]))
+	}
+	return s.send("HANGUP")
+}
+
+// StreamFile plays file, returning early if the caller presses one of escapeDigits.
+func (s *Session) StreamFile(file, escapeDigits string) (Response, error) {
+	return s.send(fmt.Sprintf(`STREAM FILE %s "%s"`, file, escapeDigits))
+}
+
+// GetData plays file and collects up to maxDigits of DTMF input, waiting
+// timeout milliseconds between digits.
+func (s *Session) GetData(file string, timeout, maxDigits int) (Response, error) {
+	return s.send(fmt.Sprintf("GET DATA %s %d %d", file, timeout, maxDigits))
+}
+
+// SayDigits speaks digits, returning early if the caller presses one of escapeDigits.
+func (s *Session) SayDigits(digits, escapeDigits string) (Response, error) {
+	return s.send(fmt.Sprintf(`SAY DIGITS %s "%s"`, digits, escapeDigits))
+}
+
+// Exec runs the dialplan application app with the given args.
+func (s *Session) Exec(app string, args ...string) (Response, error) {
+	return s.send(fmt.Sprintf(`EXEC %s "%s"`, app, strings.Join(args, ",")))
+}
+
+// SetVariable sets channel variable name to value.
+func (s *Session) SetVariable(name, value string) (Response, error) {
+	return s.send(fmt.Sprintf(`SET VARIABLE %s "%s"`, name, value))
+}
+
+// GetVariable returns the value of channel variable name.
+func (s *Session) GetVariable(name string) (string, error) {
+	resp, err := s.send(fmt.Sprintf("GET VARIABLE %s", name))
+	if err != nil {
+		return "", err
+	}
+	if resp.Result == 0 {
+		return "", fmt.Errorf("agi: no such variable %q", name)
+	}
+	return resp.Data, nil
+}
+
+// DatabaseGet reads key from family in the Asterisk database.
+func (s *Session) DatabaseGet(family, key string) (string, error) {
+	resp, err := s.send(fmt.Sprintf("DATABASE GET %s %s", family, key))
+	if err != nil {
+		return "", err
+	}
+	if resp.Result == 0 {
+		return "", fmt.Errorf("agi: no such database key %s/%s", family, key)
+	}
+	return resp.Data, nil
+}
+
+// DatabasePut stores value under key in family in the Asterisk database.
+func (s *Session) DatabasePut(family, key, value string) error {
+	_, err := s.send(fmt.Sprintf("DATABASE PUT %s %s %s", family, key, value))
+	return err
+}
+
+// DatabaseDel deletes key from family in the Asterisk database.
+func (s *Session) DatabaseDel(family, key string) error {
+	_, err := s.send(fmt.Sprintf("DATABASE DEL %s %s", family, key))
+	return err
+}
+
+// RecordFile records the channel to file in the given format until silence,
+// a digit in escapeDigits, or timeout milliseconds elapse.
+func (s *Session) RecordFile(file, format, escapeDigits string, timeout int) (Response, error) {
+	return s.send(fmt.Sprintf(`RECORD FILE %s %s "%s" %d`, file, format, escapeDigits, timeout))
+}
+
+// WaitForDigit waits up to timeout milliseconds for a single DTMF digit.
+func (s *Session) WaitForDigit(timeout int) (Response, error) {
+	return s.send(fmt.Sprintf("WAIT FOR DIGIT %d", timeout))
+}
@@ -0,0 +1,94 @@
+package agi
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// newTestSession wires a Session to one end of a net.Pipe and hands the
+// other end to drive, which plays the role of Asterisk: it receives the
+// env block first, then drive takes over reading commands and writing
+// replies (or closing the connection) for the rest of the test.
+func newTestSession(t *testing.T, drive func(conn net.Conn, r *bufio.Reader)) *Session {
+	t.Helper()
+
+	asterisk, app := net.Pipe()
+
+	go func() {
+		fmt.Fprint(asterisk, "agi_network: yes\nagi_network_script: test\n\n")
+		drive(asterisk, bufio.NewReader(asterisk))
+	}()
+
+	sess, err := NewSession(app)
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+
+	return sess
+}
+
+func readCommand(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Errorf("reading command: %v", err)
+		return ""
+	}
+
+	return strings.TrimRight(line, "\r\n")
+}
+
+func TestSessionAnswerSuccess(t *testing.T) {
+	sess := newTestSession(t, func(conn net.Conn, r *bufio.Reader) {
+		if cmd := readCommand(t, r); cmd != "ANSWER" {
+			t.Errorf("got command %q, want ANSWER", cmd)
+		}
+		conn.Write([]byte("200 result=0\n"))
+	})
+
+	resp, err := sess.Answer()
+	if err != nil {
+		t.Fatalf("Answer: %v", err)
+	}
+	if resp.Code != 200 || resp.Result != 0 {
+		t.Fatalf("got %+v, want Code=200 Result=0", resp)
+	}
+}
+
+func TestSessionCommandErrorOnNon200Reply(t *testing.T) {
+	sess := newTestSession(t, func(conn net.Conn, r *bufio.Reader) {
+		readCommand(t, r)
+		conn.Write([]byte("510 Invalid or unknown command\n"))
+	})
+
+	_, err := sess.SetVariable("FOO", "bar")
+	if err == nil {
+		t.Fatal("SetVariable: want error, got nil")
+	}
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("got error of type %T, want *CommandError", err)
+	}
+	if cmdErr.Code != 510 {
+		t.Fatalf("Code = %d, want 510", cmdErr.Code)
+	}
+}
+
+func TestSessionHangupDuringCommand(t *testing.T) {
+	sess := newTestSession(t, func(conn net.Conn, r *bufio.Reader) {
+		readCommand(t, r)
+		conn.Write([]byte("HANGUP\n"))
+		conn.Close()
+	})
+
+	_, err := sess.Answer()
+	if !errors.Is(err, ErrHangup) {
+		t.Fatalf("Answer = %v, want ErrHangup", err)
+	}
+}